@@ -0,0 +1,81 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// AdminServer exposes Prometheus metrics, pprof profiles, and a healthz
+// endpoint on a port separate from the EchoVR API and login proxy, so
+// operators can scrape/profile the wrapper without going through the
+// game-facing ports.
+type AdminServer struct {
+	addr    string
+	logger  *zap.SugaredLogger
+	healthy atomic.Bool
+}
+
+// NewAdminServer creates an AdminServer listening on addr.
+func NewAdminServer(addr string) *AdminServer {
+	return &AdminServer{
+		addr:   addr,
+		logger: logger.With("component", "admin"),
+	}
+}
+
+// SetHealthy sets the result /healthz reports. It starts out unhealthy so
+// /healthz reflects the wrapper's own readiness, not just "process is up".
+func (a *AdminServer) SetHealthy(healthy bool) {
+	a.healthy.Store(healthy)
+}
+
+func (a *AdminServer) ListenAndServe(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", a.handleHealthz)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	srv := &http.Server{Addr: a.addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close() //nolint:errcheck
+	}()
+
+	a.logger.Infof("Admin server listening on %s", a.addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		a.logger.Errorf("admin server stopped: %v", err)
+	}
+}
+
+func (a *AdminServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if !a.healthy.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
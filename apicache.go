@@ -0,0 +1,145 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// APICachingProxy serves EchoVR's /session HTTP API behind a short-lived
+// cache. EchoVR's own /session payloads are large and highly repetitive, so
+// the cached copy is kept snappy-compressed in memory and only decompressed
+// when actually serving a request. Concurrent misses are coalesced with
+// singleflight so a burst of requests produces a single upstream GET.
+type APICachingProxy struct {
+	mu         sync.RWMutex
+	cachedData []byte // snappy-compressed
+	cachedAt   time.Time
+
+	originPort int
+	listenPort int
+	ttl        time.Duration
+
+	logger *zap.SugaredLogger
+	client *http.Client
+	group  singleflight.Group
+}
+
+func NewAPICachingProxy(originPort, proxyPort *int, ttl time.Duration) *APICachingProxy {
+	return &APICachingProxy{
+		originPort: *originPort,
+		listenPort: *proxyPort,
+		ttl:        ttl,
+		logger:     logger.With("component", "apiproxy"),
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxConnsPerHost: 1,
+				MaxIdleConns:    1,
+				IdleConnTimeout: 30 * time.Second,
+			},
+		},
+	}
+}
+
+func (p *APICachingProxy) ListenAndProxy(ctx context.Context) {
+	http.HandleFunc("/session", p.handleRequest)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", p.listenPort), nil); err != nil {
+		p.logger.Errorf("api caching proxy listener stopped: %v", err)
+	}
+}
+
+// queryAPI queries EchoVR's /session endpoint, honoring ctx, and returns the
+// response snappy-compressed for caching.
+func (p *APICachingProxy) queryAPI(ctx context.Context) ([]byte, error) {
+	url := fmt.Sprintf("http://127.0.0.1:%d/session", p.originPort)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("evr GET error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("evr read error: %w", err)
+	}
+
+	return snappy.Encode(nil, body), nil
+}
+
+func (p *APICachingProxy) handleRequest(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	p.mu.RLock()
+	compressed, fresh := p.cachedData, time.Since(p.cachedAt) < p.ttl
+	p.mu.RUnlock()
+
+	if fresh && compressed != nil {
+		cacheHits.Inc()
+	} else {
+		cacheMisses.Inc()
+
+		leader := false
+		v, err, shared := p.group.Do("session", func() (interface{}, error) {
+			leader = true
+			return p.queryAPI(r.Context())
+		})
+		if shared && !leader {
+			cacheCoalesced.Inc()
+		}
+
+		if err != nil {
+			p.logger.Errorw("could not query EchoVR API, serving stale cache if available", "error", err)
+			if compressed == nil {
+				http.Error(w, "upstream EchoVR API unavailable", http.StatusBadGateway)
+				return
+			}
+		} else {
+			compressed = v.([]byte)
+			p.mu.Lock()
+			p.cachedData = compressed
+			p.cachedAt = time.Now()
+			p.mu.Unlock()
+		}
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		p.logger.Errorw("could not decompress cached session payload", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+
+	p.logger.Debugw("handled /session request", "path", r.URL.Path, "latency", time.Since(start))
+}
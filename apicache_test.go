@@ -0,0 +1,121 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestAPICachingProxy builds an APICachingProxy backed by origin, a real
+// httptest server standing in for EchoVR's HTTP API.
+func newTestAPICachingProxy(t *testing.T, origin *httptest.Server, ttl time.Duration) *APICachingProxy {
+	t.Helper()
+	u, err := url.Parse(origin.URL)
+	if err != nil {
+		t.Fatalf("could not parse origin URL: %v", err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatalf("could not parse origin port: %v", err)
+	}
+	return NewAPICachingProxy(&port, &port, ttl)
+}
+
+func TestAPICachingProxyServesCachedResponseWithinTTL(t *testing.T) {
+	var hits atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer origin.Close()
+
+	p := newTestAPICachingProxy(t, origin, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		p.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/session", nil))
+		if rec.Body.String() != `{"ok":true}` {
+			t.Fatalf("request #%d: body = %q, want %q", i, rec.Body.String(), `{"ok":true}`)
+		}
+	}
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("origin saw %d requests, want 1 (subsequent requests should hit the cache)", got)
+	}
+}
+
+func TestAPICachingProxyRequeriesAfterTTLExpires(t *testing.T) {
+	var hits atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer origin.Close()
+
+	p := newTestAPICachingProxy(t, origin, 10*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	p.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/session", nil))
+
+	time.Sleep(20 * time.Millisecond)
+
+	rec = httptest.NewRecorder()
+	p.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/session", nil))
+
+	if got := hits.Load(); got != 2 {
+		t.Errorf("origin saw %d requests, want 2 (cache should have expired)", got)
+	}
+}
+
+func TestAPICachingProxyCoalescesConcurrentMisses(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer origin.Close()
+
+	p := newTestAPICachingProxy(t, origin, time.Hour)
+
+	const concurrency = 5
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			p.handleRequest(rec, httptest.NewRequest(http.MethodGet, "/session", nil))
+		}()
+	}
+
+	// Give every goroutine a chance to reach the origin call before letting
+	// any of them finish, so they're all coalesced onto the same request.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("origin saw %d requests, want 1 (concurrent misses should coalesce)", got)
+	}
+}
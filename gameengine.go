@@ -0,0 +1,102 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// GameEngine is a struct that represents the game engine process. Start can
+// be called more than once: each call builds a fresh *exec.Cmd, which lets a
+// Supervisor restart a crashed engine.
+type GameEngine struct {
+	sync.RWMutex
+	Command    *exec.Cmd
+	BinaryPath string
+	Arguments  []string
+}
+
+func NewGameEngine(path string, args []string) *GameEngine {
+	return &GameEngine{
+		BinaryPath: path,
+		Arguments:  args,
+	}
+}
+
+// Start launches the game engine binary and blocks until it exits or ctx is
+// canceled, returning the exit error (if any) instead of calling
+// log.Fatalf, so a Supervisor can decide whether to restart it. The game's
+// stdout, stderr, and its own log file are tapped by a LogTap and forwarded
+// to the structured logger with a "source" field.
+func (e *GameEngine) Start(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, e.BinaryPath, e.Arguments...)
+
+	tap, err := NewLogTap(logger.With("component", "gameengine"))
+	if err != nil {
+		return fmt.Errorf("could not set up log tap: %w", err)
+	}
+	defer tap.Close()
+
+	cmd.Args = append(cmd.Args, "-logpath", tap.GameLogPath())
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("could not get stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("could not get stderr pipe: %w", err)
+	}
+
+	// The FIFO/log file must exist before echovr.exe opens it for writing,
+	// so the tap is wired up before cmd.Start.
+	if err := tap.Start(stdout, stderr); err != nil {
+		return fmt.Errorf("could not start log tap: %w", err)
+	}
+
+	e.Lock()
+	e.Command = cmd
+	logger.Infof("Starting game engine with command: %s", strings.Join(cmd.Args, " "))
+	if err := cmd.Start(); err != nil {
+		e.Unlock()
+		return fmt.Errorf("could not start game engine: %w", err)
+	}
+	e.Unlock()
+
+	if err := cmd.Wait(); err != nil {
+		if recent := tap.RecentLines(); len(recent) > 0 {
+			logger.Errorw("game engine crashed, dumping recent log lines", "component", "gameengine", "recent_lines", recent)
+		}
+		return fmt.Errorf("game engine exited with error: %w", err)
+	}
+	return nil
+}
+
+// Stop kills the game engine process, if it is running.
+func (e *GameEngine) Stop() {
+	e.Lock()
+	defer e.Unlock()
+	if e.Command != nil && e.Command.Process != nil {
+		if err := e.Command.Process.Kill(); err != nil {
+			logger.Errorf("Could not kill game engine: %v", err)
+			return
+		}
+	}
+	logger.Info("Game engine process has been killed")
+}
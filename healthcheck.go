@@ -0,0 +1,98 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HealthChecker polls an HTTP endpoint until it has observed
+// successThreshold consecutive successful responses. It is used to gate
+// s.Ready() on EchoVR actually answering requests, instead of the blind
+// --readyDelaySec sleep.
+type HealthChecker struct {
+	url              string
+	interval         time.Duration
+	timeout          time.Duration
+	successThreshold int
+	client           *http.Client
+	log              *zap.SugaredLogger
+}
+
+// NewHealthChecker creates a HealthChecker that polls url every interval,
+// timing out each individual request after timeout.
+func NewHealthChecker(url string, interval, timeout time.Duration, successThreshold int) *HealthChecker {
+	return &HealthChecker{
+		url:              url,
+		interval:         interval,
+		timeout:          timeout,
+		successThreshold: successThreshold,
+		client:           &http.Client{Timeout: timeout},
+		log:              logger.With("component", "readyprobe"),
+	}
+}
+
+// WaitUntilHealthy polls until successThreshold consecutive probes succeed,
+// or ctx is done (e.g. because of a --readyProbeDeadline timeout), in which
+// case it returns ctx.Err().
+func (h *HealthChecker) WaitUntilHealthy(ctx context.Context) error {
+	consecutive := 0
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		if h.probe(ctx) {
+			consecutive++
+			h.log.Debugw("ready probe succeeded", "consecutive", consecutive, "threshold", h.successThreshold)
+			if consecutive >= h.successThreshold {
+				return nil
+			}
+		} else {
+			consecutive = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe issues a single GET against h.url and reports whether it returned a
+// successful (2xx) response within h.timeout.
+func (h *HealthChecker) probe(ctx context.Context) bool {
+	reqCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, h.url, nil)
+	if err != nil {
+		h.log.Errorf("could not build ready probe request: %v", err)
+		return false
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.log.Debugf("ready probe request failed: %v", err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
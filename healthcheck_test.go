@@ -0,0 +1,114 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckerProbe(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer okServer.Close()
+
+	badServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer badServer.Close()
+
+	okChecker := NewHealthChecker(okServer.URL, time.Millisecond, time.Second, 1)
+	if !okChecker.probe(context.Background()) {
+		t.Error("probe against a 200 response: want true, got false")
+	}
+
+	badChecker := NewHealthChecker(badServer.URL, time.Millisecond, time.Second, 1)
+	if badChecker.probe(context.Background()) {
+		t.Error("probe against a 503 response: want false, got true")
+	}
+
+	unreachableChecker := NewHealthChecker("http://127.0.0.1:1", time.Millisecond, 10*time.Millisecond, 1)
+	if unreachableChecker.probe(context.Background()) {
+		t.Error("probe against an unreachable host: want false, got true")
+	}
+}
+
+func TestHealthCheckerWaitUntilHealthySucceedsAfterThreshold(t *testing.T) {
+	var successes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		successes.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := NewHealthChecker(server.URL, time.Millisecond, time.Second, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := checker.WaitUntilHealthy(ctx); err != nil {
+		t.Fatalf("WaitUntilHealthy: %v", err)
+	}
+	if got := successes.Load(); got < 3 {
+		t.Errorf("server saw %d requests, want at least 3", got)
+	}
+}
+
+func TestHealthCheckerWaitUntilHealthyResetsOnFailure(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		// Fail the 2nd request only, so a naive counter (instead of a
+		// consecutive-success counter) would wrongly report healthy too
+		// soon.
+		if n == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := NewHealthChecker(server.URL, time.Millisecond, time.Second, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := checker.WaitUntilHealthy(ctx); err != nil {
+		t.Fatalf("WaitUntilHealthy: %v", err)
+	}
+	if got := calls.Load(); got < 4 {
+		t.Errorf("server saw %d requests, want at least 4 (the failure should have reset the streak)", got)
+	}
+}
+
+func TestHealthCheckerWaitUntilHealthyReturnsCtxErrOnDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	checker := NewHealthChecker(server.URL, time.Millisecond, time.Second, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := checker.WaitUntilHealthy(ctx)
+	if err == nil {
+		t.Fatal("WaitUntilHealthy: want error once the deadline passes, got nil")
+	}
+}
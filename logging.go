@@ -0,0 +1,55 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logger is the process-wide structured logger. It is configured once in
+// main() from the --logLevel and --logFormat flags, then narrowed with
+// logger.With("component", ...) for each subsystem so log lines can be
+// filtered by component in Loki/ELK.
+var logger *zap.SugaredLogger
+
+// newLogger builds a *zap.SugaredLogger at the given level ("debug", "info",
+// "warn", "error") in either "json" (production, one object per line) or
+// "console" (human-readable, for local runs) format.
+func newLogger(level, format string) (*zap.SugaredLogger, error) {
+	var zapLevel zapcore.Level
+	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid logLevel %q: %w", level, err)
+	}
+
+	var cfg zap.Config
+	switch format {
+	case "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("invalid logFormat %q: must be \"json\" or \"console\"", format)
+	}
+	cfg.Level = zap.NewAtomicLevelAt(zapLevel)
+
+	l, err := cfg.Build()
+	if err != nil {
+		return nil, fmt.Errorf("could not build logger: %w", err)
+	}
+	return l.Sugar(), nil
+}
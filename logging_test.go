@@ -0,0 +1,44 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestNewLoggerLevelsAndFormats(t *testing.T) {
+	for _, format := range []string{"json", "console"} {
+		for _, level := range []string{"debug", "info", "warn", "error"} {
+			l, err := newLogger(level, format)
+			if err != nil {
+				t.Errorf("newLogger(%q, %q): unexpected error: %v", level, format, err)
+				continue
+			}
+			if l == nil {
+				t.Errorf("newLogger(%q, %q): got nil logger", level, format)
+			}
+		}
+	}
+}
+
+func TestNewLoggerInvalidLevel(t *testing.T) {
+	if _, err := newLogger("verbose", "console"); err == nil {
+		t.Fatal("newLogger with an invalid level: want error, got nil")
+	}
+}
+
+func TestNewLoggerInvalidFormat(t *testing.T) {
+	if _, err := newLogger("info", "xml"); err == nil {
+		t.Fatal("newLogger with an invalid format: want error, got nil")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// logTapRingCapacity is how many recent lines (across all sources) are
+	// kept for crash diagnostics.
+	logTapRingCapacity = 200
+	// logTapMaxScanTokenSize raises bufio.Scanner's default 64KiB line
+	// limit, since EchoVR can emit multi-megabyte stack traces as a single
+	// log line.
+	logTapMaxScanTokenSize = 4 << 20
+	// logTapScanBufferSize is the scanner's initial buffer; it grows up to
+	// logTapMaxScanTokenSize as needed.
+	logTapScanBufferSize = 64 * 1024
+)
+
+// LogTap multiplexes the game engine's stdout, stderr, and its own log
+// output (a FIFO on Unix, a tailed file on Windows) into the structured
+// logger, tagging every line with a "source" field rather than blindly
+// re-emitting it. It replaces the three goroutines that used to race
+// against a FIFO that was opened for reading before it was even created.
+type LogTap struct {
+	log  *zap.SugaredLogger
+	ring *ringBuffer
+	dir  string
+	wg   sync.WaitGroup
+}
+
+// NewLogTap creates a LogTap backed by a fresh per-run tempdir (used to
+// hold the game's FIFO/log file), cleaned up by Close.
+func NewLogTap(componentLog *zap.SugaredLogger) (*LogTap, error) {
+	dir, err := os.MkdirTemp("", "evr-logtap-*")
+	if err != nil {
+		return nil, err
+	}
+	return &LogTap{
+		log:  componentLog,
+		ring: newRingBuffer(logTapRingCapacity),
+		dir:  dir,
+	}, nil
+}
+
+// GameLogPath is the path the game engine should be told (via -logpath) to
+// write its own log to.
+func (t *LogTap) GameLogPath() string {
+	return filepath.Join(t.dir, "game.log")
+}
+
+// Start taps stdout and stderr, then starts the platform-specific game log
+// reader (FIFO on Unix, tailed file on Windows). It must be called before
+// the game process is started, since the Unix FIFO has to exist first.
+func (t *LogTap) Start(stdout, stderr io.Reader) error {
+	t.tap("stdout", stdout)
+	t.tap("stderr", stderr)
+	return t.startGameLog()
+}
+
+// tap scans r line by line, tagging each line with source and forwarding it
+// to both the ring buffer and the structured logger.
+func (t *LogTap) tap(source string, r io.Reader) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, logTapScanBufferSize), logTapMaxScanTokenSize)
+		for scanner.Scan() {
+			line := scanner.Text()
+			t.ring.Add(line)
+			t.log.Infow(line, "source", source)
+		}
+		if err := scanner.Err(); err != nil {
+			t.log.Warnw("log tap reader stopped", "source", source, "error", err)
+		}
+	}()
+}
+
+// RecentLines returns the most recent buffered log lines across all
+// sources, oldest first.
+func (t *LogTap) RecentLines() []string {
+	return t.ring.Lines()
+}
+
+// Close removes the per-run tempdir holding the game's FIFO/log file. It
+// does not wait for the tap goroutines to drain, since the game process
+// (and therefore its pipes) is expected to already be gone by the time
+// Close is called.
+func (t *LogTap) Close() {
+	os.RemoveAll(t.dir)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// startGameLog creates the FIFO the game engine writes its log to, mode
+// 0600, and taps it with a non-blocking reader. The FIFO is created before
+// the game process is started, since echovr.exe opening it for writing
+// would otherwise race the wrapper creating it.
+func (t *LogTap) startGameLog() error {
+	path := t.GameLogPath()
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return fmt.Errorf("could not create log fifo: %w", err)
+	}
+
+	// Opened O_RDWR, not O_RDONLY: a FIFO with no writer ever attached
+	// reports EOF on read, and since echovr.exe opening the write end
+	// races the wrapper here, an O_RDONLY open can see zero writers for
+	// a while after open. Holding our own write fd open keeps the writer
+	// count above zero for the FIFO's whole lifetime, so reads block (or,
+	// with O_NONBLOCK, return EAGAIN) instead of EOF until the game
+	// actually writes something.
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return fmt.Errorf("could not open log fifo: %w", err)
+	}
+
+	t.tap("game", &nonblockingFifoReader{f: f})
+	return nil
+}
+
+// nonblockingFifoReader adapts an O_NONBLOCK FIFO file descriptor to
+// io.Reader, retrying on EAGAIN instead of surfacing it as EOF. The file
+// must be opened O_RDWR (see startGameLog) so the kernel never reports
+// EOF for want of a writer.
+type nonblockingFifoReader struct {
+	f *os.File
+}
+
+func (r *nonblockingFifoReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || (err != nil && !errors.Is(err, syscall.EAGAIN)) {
+			return n, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestNonblockingFifoReaderSurvivesWriterAfterOpen reproduces the scenario
+// startGameLog has to handle: the reader opens the FIFO before the game
+// engine has ever opened the write end. A reader opened O_RDONLY sees EOF
+// immediately in that window; opening O_RDWR (as startGameLog does) must
+// keep the FIFO open for reading across a writer showing up late.
+func TestNonblockingFifoReaderSurvivesWriterAfterOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.log")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Fatalf("Mkfifo: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+	r := &nonblockingFifoReader{f: f}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(200 * time.Millisecond)
+		w, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Errorf("writer OpenFile: %v", err)
+			return
+		}
+		defer w.Close()
+		if _, err := w.Write([]byte("hello\n")); err != nil {
+			t.Errorf("Write: %v", err)
+		}
+	}()
+
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	<-done
+	if err != nil {
+		t.Fatalf("Read returned error before any data arrived: %v", err)
+	}
+	if got := string(buf[:n]); got != "hello\n" {
+		t.Fatalf("Read = %q, want %q", got, "hello\n")
+	}
+}
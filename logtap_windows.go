@@ -0,0 +1,57 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// startGameLog creates a plain file for the game engine to write its log
+// to and tails it, since syscall.Mkfifo has no Windows equivalent and
+// EchoVR itself is a Windows binary.
+func (t *LogTap) startGameLog() error {
+	path := t.GameLogPath()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create log file: %w", err)
+	}
+
+	t.tap("game", &tailReader{f: f})
+	return nil
+}
+
+// tailReader polls a growing file for new data, since a plain read at the
+// current end of file returns io.EOF rather than blocking for more.
+type tailReader struct {
+	f *os.File
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
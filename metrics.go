@@ -0,0 +1,100 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+
+	coresdk "agones.dev/agones/pkg/sdk"
+	sdk "agones.dev/agones/sdks/go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Package-level Prometheus collectors, registered once at startup so that
+// supervised services (which are created once per run, but keyed by name)
+// don't collide on duplicate registration. The session-cache hit ratio is
+// deliberately not its own metric: it's cacheHits / (cacheHits +
+// cacheMisses), which Prometheus can compute with a rate() query over those
+// two counters.
+var (
+	cacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "evr_session_cache_hits_total",
+		Help: "Number of /session requests served from the session cache.",
+	})
+	cacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "evr_session_cache_misses_total",
+		Help: "Number of /session requests that had to query EchoVR's HTTP API.",
+	})
+	cacheCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "evr_session_cache_coalesced_total",
+		Help: "Number of /session cache misses that were coalesced into an in-flight upstream request.",
+	})
+
+	supervisorUpGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "evr_supervised_service_up",
+		Help: "1 if the named supervised service is currently running, 0 otherwise.",
+	}, []string{"service"})
+
+	supervisorRestartsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "evr_supervised_service_restarts_total",
+		Help: "Number of times the named supervised service has been restarted.",
+	}, []string{"service"})
+
+	loginProxyBytesIn = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "evr_loginproxy_bytes_in_total",
+		Help: "Bytes received from the login service and forwarded to EchoVR.",
+	})
+	loginProxyBytesOut = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "evr_loginproxy_bytes_out_total",
+		Help: "Bytes received from EchoVR and forwarded to the login service.",
+	})
+
+	healthPingLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "evr_health_ping_latency_seconds",
+		Help: "Latency of SDK Health() calls.",
+	})
+
+	gameServerAllocations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "evr_gameserver_allocations_total",
+		Help: "Number of times this gameserver has been allocated since the wrapper started.",
+	})
+)
+
+// watchAllocationMetrics increments gameServerAllocations every time the
+// gameserver transitions to allocated. It runs independently of
+// --automaticShutdownDelaySec/Min so the metric is always available.
+func watchAllocationMetrics(s *sdk.SDK) {
+	gs, err := s.GameServer()
+	if err != nil {
+		logger.Errorf("Could not get game server for allocation metrics: %v", err)
+		return
+	}
+
+	m := sync.Mutex{} // protects lastAllocated
+	lastAllocated := gs.ObjectMeta.Annotations["agones.dev/last-allocated"]
+
+	if err := s.WatchGameServer(func(gs *coresdk.GameServer) {
+		m.Lock()
+		defer m.Unlock()
+		la := gs.ObjectMeta.Annotations["agones.dev/last-allocated"]
+		if lastAllocated != la {
+			lastAllocated = la
+			gameServerAllocations.Inc()
+		}
+	}); err != nil {
+		logger.Errorf("Could not watch Game Server for allocation metrics: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// proxyProtocolV2Signature is the fixed 12-byte preamble that opens every
+// PROXY protocol v2 header.
+var proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// writeProxyProtocolHeader writes a PROXY protocol header (v1 or v2) to w,
+// describing a TCP connection from src to dst, before any proxied data is
+// written. This lets the upstream login service see the real connection
+// endpoints instead of the wrapper's own loopback address.
+func writeProxyProtocolHeader(w io.Writer, version string, src, dst net.Addr) error {
+	srcTCP, ok := src.(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("source address %v is not a TCP address", src)
+	}
+	dstTCP, ok := dst.(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("destination address %v is not a TCP address", dst)
+	}
+
+	switch version {
+	case "v1":
+		return writeProxyProtocolV1(w, srcTCP, dstTCP)
+	case "v2":
+		return writeProxyProtocolV2(w, srcTCP, dstTCP)
+	default:
+		return fmt.Errorf("unsupported PROXY protocol version %q", version)
+	}
+}
+
+func writeProxyProtocolV1(w io.Writer, src, dst *net.TCPAddr) error {
+	family := "TCP4"
+	if src.IP.To4() == nil {
+		family = "TCP6"
+	}
+	_, err := fmt.Fprintf(w, "PROXY %s %s %s %d %d\r\n", family, src.IP.String(), dst.IP.String(), src.Port, dst.Port)
+	return err
+}
+
+func writeProxyProtocolV2(w io.Writer, src, dst *net.TCPAddr) error {
+	var buf bytes.Buffer
+	buf.Write(proxyProtocolV2Signature)
+	buf.WriteByte(0x21) // version 2, command PROXY
+
+	srcIP4, dstIP4 := src.IP.To4(), dst.IP.To4()
+	if srcIP4 != nil && dstIP4 != nil {
+		buf.WriteByte(0x11) // AF_INET, SOCK_STREAM
+		binary.Write(&buf, binary.BigEndian, uint16(12))
+		buf.Write(srcIP4)
+		buf.Write(dstIP4)
+	} else {
+		buf.WriteByte(0x21) // AF_INET6, SOCK_STREAM
+		binary.Write(&buf, binary.BigEndian, uint16(36))
+		buf.Write(src.IP.To16())
+		buf.Write(dst.IP.To16())
+	}
+	binary.Write(&buf, binary.BigEndian, uint16(src.Port))
+	binary.Write(&buf, binary.BigEndian, uint16(dst.Port))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestWriteProxyProtocolV1IPv4(t *testing.T) {
+	var buf bytes.Buffer
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 56789}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 6789}
+
+	if err := writeProxyProtocolV1(&buf, src, dst); err != nil {
+		t.Fatalf("writeProxyProtocolV1: %v", err)
+	}
+
+	want := "PROXY TCP4 192.168.1.1 10.0.0.1 56789 6789\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeProxyProtocolV1 = %q, want %q", got, want)
+	}
+}
+
+func TestWriteProxyProtocolV1IPv6(t *testing.T) {
+	var buf bytes.Buffer
+	src := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 56789}
+	dst := &net.TCPAddr{IP: net.ParseIP("::2"), Port: 6789}
+
+	if err := writeProxyProtocolV1(&buf, src, dst); err != nil {
+		t.Fatalf("writeProxyProtocolV1: %v", err)
+	}
+
+	want := "PROXY TCP6 ::1 ::2 56789 6789\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeProxyProtocolV1 = %q, want %q", got, want)
+	}
+}
+
+func TestWriteProxyProtocolV2IPv4(t *testing.T) {
+	var buf bytes.Buffer
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 56789}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 6789}
+
+	if err := writeProxyProtocolV2(&buf, src, dst); err != nil {
+		t.Fatalf("writeProxyProtocolV2: %v", err)
+	}
+
+	want := []byte{
+		0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A, // signature
+		0x21,       // version 2, command PROXY
+		0x11,       // AF_INET, SOCK_STREAM
+		0x00, 0x0C, // address length = 12
+		192, 168, 1, 1, // src IPv4
+		10, 0, 0, 1, // dst IPv4
+		0xDD, 0xD5, // src port 56789
+		0x1A, 0x85, // dst port 6789
+	}
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("writeProxyProtocolV2 =\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestWriteProxyProtocolV2IPv6(t *testing.T) {
+	var buf bytes.Buffer
+	src := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 1}
+	dst := &net.TCPAddr{IP: net.ParseIP("::2"), Port: 2}
+
+	if err := writeProxyProtocolV2(&buf, src, dst); err != nil {
+		t.Fatalf("writeProxyProtocolV2: %v", err)
+	}
+
+	got := buf.Bytes()
+	if len(got) != 12+1+1+2+16+16+2+2 {
+		t.Fatalf("writeProxyProtocolV2 wrote %d bytes, want %d", len(got), 12+1+1+2+16+16+2+2)
+	}
+	if got[12] != 0x21 {
+		t.Errorf("version/command byte = %#x, want 0x21", got[12])
+	}
+	if got[13] != 0x21 {
+		t.Errorf("family/transport byte = %#x, want 0x21 (AF_INET6, SOCK_STREAM)", got[13])
+	}
+	if got[14] != 0x00 || got[15] != 0x24 {
+		t.Errorf("address length = % x, want 00 24 (36)", got[14:16])
+	}
+}
+
+func TestWriteProxyProtocolHeaderRejectsNonTCPAddr(t *testing.T) {
+	var buf bytes.Buffer
+	src := &net.UnixAddr{Name: "/tmp/sock"}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 6789}
+
+	if err := writeProxyProtocolHeader(&buf, "v1", src, dst); err == nil {
+		t.Fatal("writeProxyProtocolHeader with a non-TCP source: want error, got nil")
+	}
+}
+
+func TestWriteProxyProtocolHeaderRejectsUnsupportedVersion(t *testing.T) {
+	var buf bytes.Buffer
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 56789}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 6789}
+
+	if err := writeProxyProtocolHeader(&buf, "v3", src, dst); err == nil {
+		t.Fatal("writeProxyProtocolHeader with an unsupported version: want error, got nil")
+	}
+}
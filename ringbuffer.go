@@ -0,0 +1,58 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "sync"
+
+// ringBuffer is a fixed-capacity, concurrency-safe buffer of the most
+// recently added strings, oldest entries dropped first.
+type ringBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	return &ringBuffer{lines: make([]string, capacity)}
+}
+
+// Add appends a line, overwriting the oldest entry once the buffer is full.
+func (r *ringBuffer) Add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Lines returns the buffered lines in insertion order, oldest first.
+func (r *ringBuffer) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		out := make([]string, r.next)
+		copy(out, r.lines[:r.next])
+		return out
+	}
+
+	out := make([]string, len(r.lines))
+	n := copy(out, r.lines[r.next:])
+	copy(out[n:], r.lines[:r.next])
+	return out
+}
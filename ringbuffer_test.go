@@ -0,0 +1,70 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRingBufferBelowCapacity(t *testing.T) {
+	r := newRingBuffer(5)
+	r.Add("a")
+	r.Add("b")
+
+	got := r.Lines()
+	want := []string{"a", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+}
+
+func TestRingBufferOverwritesOldestOnceFull(t *testing.T) {
+	r := newRingBuffer(3)
+	for _, line := range []string{"a", "b", "c", "d", "e"} {
+		r.Add(line)
+	}
+
+	got := r.Lines()
+	want := []string{"c", "d", "e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+}
+
+func TestRingBufferEmpty(t *testing.T) {
+	r := newRingBuffer(3)
+	if got := r.Lines(); len(got) != 0 {
+		t.Errorf("Lines() on an empty buffer = %v, want empty", got)
+	}
+}
+
+func TestRingBufferConcurrentAdd(t *testing.T) {
+	r := newRingBuffer(100)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Add("x")
+		}()
+	}
+	wg.Wait()
+
+	if got := len(r.Lines()); got != 50 {
+		t.Errorf("Lines() length = %d, want 50", got)
+	}
+}
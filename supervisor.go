@@ -0,0 +1,176 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	sdk "agones.dev/agones/sdks/go"
+	"go.uber.org/zap"
+)
+
+const (
+	supervisorMinBackoff    = time.Second
+	supervisorMaxBackoff    = 60 * time.Second
+	supervisorHealthyReset  = 10 * time.Minute
+	supervisorMaxFailures   = 5
+	supervisorFailureWindow = 5 * time.Minute
+)
+
+// Supervisor restarts a service with exponential backoff, Suture-style, so a
+// crash of the game engine or a failure of the login proxy's listener
+// doesn't call log.Fatalf and kill the whole wrapper. Restart counts are recorded as
+// Agones annotations so operators can spot a flapping service with
+// `kubectl describe gameserver` instead of tailing logs.
+type Supervisor struct {
+	name string
+	sdk  *sdk.SDK
+	log  *zap.SugaredLogger
+
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	healthyReset  time.Duration
+	maxFailures   int
+	failureWindow time.Duration
+}
+
+// NewSupervisor creates a Supervisor for the named service, reporting
+// restarts through s.
+func NewSupervisor(name string, s *sdk.SDK) *Supervisor {
+	return &Supervisor{
+		name:          name,
+		sdk:           s,
+		log:           logger.With("component", "supervisor", "service", name),
+		minBackoff:    supervisorMinBackoff,
+		maxBackoff:    supervisorMaxBackoff,
+		healthyReset:  supervisorHealthyReset,
+		maxFailures:   supervisorMaxFailures,
+		failureWindow: supervisorFailureWindow,
+	}
+}
+
+// Run calls fn, restarting it with exponential backoff (starting at
+// minBackoff, capped at maxBackoff) whenever it returns, until ctx is
+// canceled. The backoff resets once a run has stayed up for healthyReset.
+// If maxFailures restarts happen within failureWindow, Run gives up and
+// shuts the gameserver down cleanly via sdk.Shutdown() instead of letting
+// the wrapper flap indefinitely; Agones will evict the pod and the wrapper
+// exits normally when it receives SIGTERM.
+func (sv *Supervisor) Run(ctx context.Context, fn func(ctx context.Context) error) {
+	bo := newSupervisorBackoff(sv.minBackoff, sv.maxBackoff, sv.healthyReset, sv.maxFailures, sv.failureWindow)
+	restarts := 0
+
+	for {
+		start := time.Now()
+		supervisorUpGauge.WithLabelValues(sv.name).Set(1)
+		err := fn(ctx)
+		supervisorUpGauge.WithLabelValues(sv.name).Set(0)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		restarts++
+		supervisorRestartsCounter.WithLabelValues(sv.name).Inc()
+		sv.recordRestart(restarts)
+
+		wait, giveUp := bo.onExit(time.Since(start), time.Now())
+
+		if err != nil {
+			sv.log.Errorw("service exited, restarting", "error", err, "restart_count", restarts, "backoff", wait)
+		} else {
+			sv.log.Warnw("service returned unexpectedly, restarting", "restart_count", restarts, "backoff", wait)
+		}
+
+		if giveUp {
+			sv.log.Errorf("%s failed %d times within %s, shutting down the gameserver", sv.name, bo.failures, sv.failureWindow)
+			if shutdownErr := sv.sdk.Shutdown(); shutdownErr != nil {
+				sv.log.Errorf("Could not shutdown game server: %v", shutdownErr)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// supervisorBackoff tracks the exponential-backoff and failure-window state
+// for one Supervisor, factored out of Run so it can be exercised by tests
+// without a real Agones SDK connection.
+type supervisorBackoff struct {
+	min, max, healthyReset time.Duration
+	maxFailures            int
+	failureWindow          time.Duration
+
+	backoff     time.Duration
+	failures    int
+	windowStart time.Time
+}
+
+func newSupervisorBackoff(min, max, healthyReset time.Duration, maxFailures int, failureWindow time.Duration) *supervisorBackoff {
+	return &supervisorBackoff{
+		min:           min,
+		max:           max,
+		healthyReset:  healthyReset,
+		maxFailures:   maxFailures,
+		failureWindow: failureWindow,
+		backoff:       min,
+	}
+}
+
+// onExit records that the supervised service just exited after running for
+// ranFor, and reports how long to wait before the next restart. giveUp is
+// true once maxFailures restarts have happened within failureWindow, in
+// which case wait is meaningless and the caller should stop retrying.
+func (b *supervisorBackoff) onExit(ranFor time.Duration, now time.Time) (wait time.Duration, giveUp bool) {
+	if ranFor >= b.healthyReset {
+		b.backoff = b.min
+		b.failures = 0
+		b.windowStart = time.Time{}
+	}
+
+	if b.windowStart.IsZero() || now.Sub(b.windowStart) > b.failureWindow {
+		b.windowStart = now
+		b.failures = 0
+	}
+	b.failures++
+
+	if b.failures >= b.maxFailures {
+		return 0, true
+	}
+
+	wait = b.backoff
+	b.backoff *= 2
+	if b.backoff > b.max {
+		b.backoff = b.max
+	}
+	return wait, false
+}
+
+// recordRestart annotates the gameserver with the current restart count for
+// this service.
+func (sv *Supervisor) recordRestart(count int) {
+	key := fmt.Sprintf("echotools.dev/%s-restart-count", sv.name)
+	if err := sv.sdk.SetAnnotation(key, strconv.Itoa(count)); err != nil {
+		sv.log.Warnf("Could not set restart annotation: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSupervisorBackoffDoublesAndCaps(t *testing.T) {
+	b := newSupervisorBackoff(time.Second, 8*time.Second, time.Hour, 100, time.Hour)
+	now := time.Unix(0, 0)
+
+	wantWaits := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	for i, want := range wantWaits {
+		wait, giveUp := b.onExit(time.Millisecond, now)
+		if giveUp {
+			t.Fatalf("onExit #%d: unexpected giveUp", i)
+		}
+		if wait != want {
+			t.Errorf("onExit #%d: wait = %s, want %s", i, wait, want)
+		}
+	}
+}
+
+func TestSupervisorBackoffResetsAfterHealthyRun(t *testing.T) {
+	b := newSupervisorBackoff(time.Second, time.Minute, 10*time.Second, 100, time.Hour)
+	now := time.Unix(0, 0)
+
+	if wait, _ := b.onExit(time.Millisecond, now); wait != time.Second {
+		t.Fatalf("first onExit: wait = %s, want 1s", wait)
+	}
+	if wait, _ := b.onExit(time.Millisecond, now); wait != 2*time.Second {
+		t.Fatalf("second onExit: wait = %s, want 2s", wait)
+	}
+
+	// A run that stayed up at least healthyReset resets the backoff to min.
+	if wait, _ := b.onExit(20*time.Second, now); wait != time.Second {
+		t.Fatalf("onExit after healthy run: wait = %s, want 1s (reset)", wait)
+	}
+}
+
+func TestSupervisorBackoffGivesUpAfterMaxFailuresWithinWindow(t *testing.T) {
+	b := newSupervisorBackoff(time.Second, time.Minute, time.Hour, 3, time.Minute)
+	now := time.Unix(0, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, giveUp := b.onExit(time.Millisecond, now); giveUp {
+			t.Fatalf("onExit #%d: gave up too early", i)
+		}
+	}
+
+	_, giveUp := b.onExit(time.Millisecond, now)
+	if !giveUp {
+		t.Fatal("onExit: want giveUp after maxFailures within the window")
+	}
+}
+
+func TestSupervisorBackoffDoesNotGiveUpAcrossWindows(t *testing.T) {
+	b := newSupervisorBackoff(time.Second, time.Minute, time.Hour, 2, time.Minute)
+	now := time.Unix(0, 0)
+
+	if _, giveUp := b.onExit(time.Millisecond, now); giveUp {
+		t.Fatal("onExit: unexpected giveUp on first failure")
+	}
+
+	// Next failure happens well outside the failure window: the window
+	// resets, so this does not trip maxFailures even though it's the
+	// second failure observed overall.
+	later := now.Add(2 * time.Minute)
+	if _, giveUp := b.onExit(time.Millisecond, later); giveUp {
+		t.Fatal("onExit: giveUp fired across a stale failure window")
+	}
+}
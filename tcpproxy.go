@@ -0,0 +1,155 @@
+// Copyright 2020 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// The TCPProxy forwards EchoVR's login connection to the login service.
+// When either side of a connection closes or idles past idleTimeout, both
+// ends are closed; the game engine is left running.
+type TCPProxy struct {
+	sync.RWMutex
+	localAddress  string
+	remoteAddress string
+	proxyProtocol string
+	idleTimeout   time.Duration
+	listener      net.Listener
+	log           *zap.SugaredLogger
+}
+
+// NewTCPProxy creates a TCPProxy listening on localAddress and forwarding
+// to remoteAddress. proxyProtocol is "none", "v1", or "v2": when not
+// "none", a PROXY protocol header carrying the real connection endpoints is
+// written to the upstream connection before any data is forwarded, so the
+// login service doesn't see every session as coming from the pod's own
+// loopback address.
+func NewTCPProxy(localAddress, remoteAddress, proxyProtocol string, idleTimeout time.Duration) (*TCPProxy, error) {
+	switch proxyProtocol {
+	case "none", "v1", "v2":
+	default:
+		return nil, fmt.Errorf("invalid loginProxyProtocol %q: must be none, v1, or v2", proxyProtocol)
+	}
+
+	return &TCPProxy{
+		localAddress:  localAddress,
+		remoteAddress: remoteAddress,
+		proxyProtocol: proxyProtocol,
+		idleTimeout:   idleTimeout,
+		log:           logger.With("component", "loginproxy"),
+	}, nil
+}
+
+// Start listens on p.localAddress and proxies every connection to
+// p.remoteAddress, blocking until ctx is canceled or the listener fails.
+// It returns the failure as an error instead of calling log.Fatalf, so a
+// Supervisor can restart the proxy instead of killing the wrapper.
+func (p *TCPProxy) Start(ctx context.Context, gameEngine *GameEngine, apiCachingProxy *APICachingProxy) error {
+	var err error
+	p.listener, err = net.Listen("tcp4", p.localAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", p.localAddress, err)
+	}
+	defer p.listener.Close()
+
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		p.listener.Close()
+	}()
+
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		go p.handleConnection(connCtx, conn, gameEngine, apiCachingProxy)
+	}
+}
+
+// handleConnection proxies a single login connection to the upstream login
+// service. A failed dial or a dropped connection no longer brings down the
+// wrapper with log.Fatalf: it is logged with the remote address and the
+// connection is closed, leaving the engine and the rest of the proxy
+// listening for the next attempt.
+func (p *TCPProxy) handleConnection(ctx context.Context, localConn net.Conn, gameEngine *GameEngine, apiCachingProxy *APICachingProxy) {
+	connLog := p.log.With("remote_addr", localConn.RemoteAddr().String())
+
+	remoteConn, err := net.Dial("tcp4", p.remoteAddress)
+	if err != nil {
+		connLog.Errorf("Failed to connect to %s: %v", p.remoteAddress, err)
+		localConn.Close()
+		return
+	}
+
+	if p.proxyProtocol != "none" {
+		if err := writeProxyProtocolHeader(remoteConn, p.proxyProtocol, localConn.RemoteAddr(), remoteConn.RemoteAddr()); err != nil {
+			connLog.Errorf("Failed to write PROXY protocol header: %v", err)
+			localConn.Close()
+			remoteConn.Close()
+			return
+		}
+	}
+
+	go p.forward(localConn, remoteConn, loginProxyBytesIn)
+	go p.forward(remoteConn, localConn, loginProxyBytesOut)
+
+	<-ctx.Done()
+}
+
+// forward copies src into dst until either side closes or idles for longer
+// than p.idleTimeout, closing both ends when it's done. Bytes successfully
+// copied are added to counter.
+func (p *TCPProxy) forward(dst, src net.Conn, counter prometheus.Counter) {
+	defer dst.Close()
+	defer src.Close()
+
+	n, err := io.Copy(dst, &idleTimeoutReader{Conn: src, timeout: p.idleTimeout})
+	counter.Add(float64(n))
+	if err != nil {
+		p.log.Debugf("connection forwarding stopped: %v", err)
+	}
+}
+
+// idleTimeoutReader resets the wrapped connection's read deadline before
+// every Read, so a login connection that goes quiet for longer than
+// timeout is torn down instead of held open forever.
+type idleTimeoutReader struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	if r.timeout > 0 {
+		if err := r.Conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	return r.Conn.Read(p)
+}